@@ -0,0 +1,97 @@
+package sql
+
+// Metric describes how a single value column of a query's result set is
+// converted into a datapoint.
+type Metric struct {
+	// The name that will be used for the emitted datapoint.
+	MetricName string `yaml:"metricName" validate:"required"`
+	// The name of the column (case-insensitive) that holds the value for
+	// this metric. The column must be numeric or convertible to a number.
+	ValueColumn string `yaml:"valueColumn" validate:"required"`
+	// A list of column names (case-insensitive) whose row values will be
+	// added as dimensions on the emitted datapoint.
+	DimensionColumns []string `yaml:"dimensionColumns"`
+	// Whether the value of this metric is a cumulative counter as opposed
+	// to a point-in-time gauge.
+	IsCumulative bool `yaml:"isCumulative"`
+	// The name of a column (case-insensitive) whose value should be used as
+	// the datapoint's timestamp instead of the time the row was scanned.
+	// The column can be a native timestamp type, or a string, in which case
+	// TimestampLayout must be set to a Go time layout describing how to
+	// parse it.
+	TimestampColumn string `yaml:"timestampColumn"`
+	// The Go time layout (e.g. "2006-01-02 15:04:05") used to parse
+	// TimestampColumn when it is scanned as a string rather than a native
+	// timestamp type.
+	TimestampLayout string `yaml:"timestampLayout"`
+}
+
+// Query represents a single SQL statement along with the set of metrics
+// and/or events that should be derived from its result set.
+type Query struct {
+	// The parameterized SQL query to run against the configured database.
+	Query string `yaml:"query" validate:"required"`
+	// Parameters substituted into the query's placeholders, in order.
+	Params []interface{} `yaml:"params"`
+	// The set of metrics to generate from each row returned by the query.
+	Metrics []Metric `yaml:"metrics"`
+	// The set of SignalFx events to generate from each row returned by the
+	// query, e.g. for scraping error-log or deployment tables.
+	Events []EventSpec `yaml:"events"`
+	// If set, this query is run incrementally: the cursor's current value
+	// is substituted for any CursorPlaceholder entries in Params, and
+	// advances to the greatest value seen in Cursor.Column after each row
+	// is successfully emitted. This turns the query into a tail of an
+	// append-only table instead of a full re-scan on every interval.
+	//
+	// Substitution only works for positional placeholders (Params is an
+	// ordered list substituted in by position); named binds (e.g.
+	// sql.Named or driver-specific :name/@name syntax) are not supported,
+	// since Params has no way to carry a parameter name from YAML.
+	Cursor *Cursor `yaml:"cursor"`
+	// If true, the rows returned by this query are logged at info level.
+	// Useful for debugging column names/values but noisy in production.
+	LogQueries bool `yaml:"logQueries"`
+}
+
+// CursorPlaceholder is used as a positional entry in a Query's Params to
+// mark where the current cursor value should be substituted before each
+// run. It is only recognized by position; there is no named-parameter
+// equivalent.
+const CursorPlaceholder = "$CURSOR"
+
+// Cursor configures incremental/tail-of-table querying for a Query.
+type Cursor struct {
+	// The column (case-insensitive) that tracks this query's position,
+	// e.g. an auto-increment id or a monotonically increasing timestamp.
+	Column string `yaml:"column" validate:"required"`
+	// The value to use the first time this query runs, before any cursor
+	// value has been seen or persisted, e.g. "0" or "1970-01-01T00:00:00Z".
+	// Required: an unset initial value would be substituted as an empty
+	// string on the first run, which either fails against a numeric/time
+	// column or silently matches no rows.
+	InitialValue string `yaml:"initialValue" validate:"required"`
+	// A key identifying this cursor in the monitor's on-disk checkpoint
+	// file, if CursorCheckpointPath is set. Defaults to the query text.
+	PersistenceKey string `yaml:"persistenceKey"`
+}
+
+// EventSpec describes how a row of a query's result set is converted into
+// a SignalFx event.
+type EventSpec struct {
+	// The event type that will be used for events emitted from this spec.
+	EventType string `yaml:"eventType" validate:"required"`
+	// Column names (case-insensitive) whose row values become properties on
+	// the emitted event.
+	PropertyColumns []string `yaml:"propertyColumns"`
+	// Column names (case-insensitive) whose row values become dimensions on
+	// the emitted event.
+	DimensionColumns []string `yaml:"dimensionColumns"`
+	// The name of a column (case-insensitive) whose value should be used as
+	// the event's timestamp instead of the time the row was scanned. See
+	// Metric.TimestampColumn for how it is interpreted.
+	TimestampColumn string `yaml:"timestampColumn"`
+	// The Go time layout used to parse TimestampColumn when it is scanned
+	// as a string rather than a native timestamp type.
+	TimestampLayout string `yaml:"timestampLayout"`
+}
@@ -0,0 +1,150 @@
+package sql
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cursorStore persists cursor values across agent restarts, keyed by each
+// query's Cursor.PersistenceKey. A single store is shared by every querier
+// in a monitor instance. If path is empty, values are kept in memory only
+// and a restart replays history.
+type cursorStore struct {
+	path string
+
+	mu     sync.Mutex
+	values map[string]string
+	dirty  bool
+}
+
+func newCursorStore(path string) *cursorStore {
+	s := &cursorStore{path: path, values: map[string]string{}}
+	if path == "" {
+		return s
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		// No checkpoint file yet, or it can't be read; start fresh rather
+		// than failing Configure over it.
+		return s
+	}
+	_ = json.Unmarshal(data, &s.values)
+	return s
+}
+
+func (s *cursorStore) get(key, fallback string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.values[key]; ok {
+		return v
+	}
+	return fallback
+}
+
+// set records key's new value in memory only; it does not touch disk. A
+// query with a cursor can advance its value once per emitted row, so
+// writing the checkpoint file here would mean a blocking disk write per
+// row instead of per doQuery call. Call flush once the caller is done
+// advancing cursors for this round.
+func (s *cursorStore) set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.dirty = true
+}
+
+// flush persists the current values to disk if anything has changed since
+// the last flush, and if a checkpoint path is configured.
+func (s *cursorStore) flush() {
+	if s.path == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return
+	}
+
+	data, err := json.Marshal(s.values)
+	if err != nil {
+		return
+	}
+	if err := ioutil.WriteFile(s.path, data, 0644); err != nil {
+		return
+	}
+	s.dirty = false
+}
+
+// cursorState tracks the current position of a single Query's Cursor.
+type cursorState struct {
+	column string // lower-cased Cursor.Column
+	key    string
+	store  *cursorStore
+	value  string
+}
+
+func newCursorState(c *Cursor, queryText string, store *cursorStore) *cursorState {
+	key := c.PersistenceKey
+	if key == "" {
+		key = queryText
+	}
+
+	return &cursorState{
+		column: strings.ToLower(c.Column),
+		key:    key,
+		store:  store,
+		value:  store.get(key, c.InitialValue),
+	}
+}
+
+// param returns the current cursor value coerced to the most useful type
+// for substitution into a query's Params: an int64 or time.Time if the
+// value parses as one, otherwise the raw string.
+func (cs *cursorState) param() interface{} {
+	if i, err := strconv.ParseInt(cs.value, 10, 64); err == nil {
+		return i
+	}
+	if t, err := time.Parse(time.RFC3339Nano, cs.value); err == nil {
+		return t
+	}
+	return cs.value
+}
+
+// advance moves the cursor forward to newValue if it is greater than the
+// current value, recording the change in the store's memory; it is written
+// to disk only when the store is flushed. Values are compared as int64 if
+// both parse as such, then as RFC3339 timestamps, falling back to a plain
+// string comparison otherwise.
+func (cs *cursorState) advance(newValue string) {
+	if newValue == "" || !isGreaterCursorValue(newValue, cs.value) {
+		return
+	}
+	cs.value = newValue
+	cs.store.set(cs.key, cs.value)
+}
+
+func isGreaterCursorValue(a, b string) bool {
+	if b == "" {
+		return true
+	}
+
+	if ai, aerr := strconv.ParseInt(a, 10, 64); aerr == nil {
+		if bi, berr := strconv.ParseInt(b, 10, 64); berr == nil {
+			return ai > bi
+		}
+	}
+
+	if at, aerr := time.Parse(time.RFC3339Nano, a); aerr == nil {
+		if bt, berr := time.Parse(time.RFC3339Nano, b); berr == nil {
+			return at.After(bt)
+		}
+	}
+
+	return a > b
+}
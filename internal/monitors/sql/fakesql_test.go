@@ -0,0 +1,152 @@
+package sql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// fakeColumn describes one column of a fakeDriver result set: its name and
+// the DatabaseTypeName a real driver would report for it, which is what
+// scannerForColumnType keys off of.
+type fakeColumn struct {
+	name   string
+	dbType string
+}
+
+type fakeFixture struct {
+	columns []fakeColumn
+	rows    [][]driver.Value
+}
+
+// fakeFixtures lets test-registered result sets be looked up by the DSN
+// passed to sql.Open, since database/sql.Driver.Open only receives a name.
+var fakeFixtures = struct {
+	mu      sync.Mutex
+	m       map[string]*fakeFixture
+	nextIdx int
+}{m: map[string]*fakeFixture{}}
+
+func init() {
+	sql.Register("sqltestfake", &fakeDriver{})
+}
+
+// openFakeRows opens a *sql.Rows backed by an in-process fake driver, so
+// tests can exercise getRowSlice's use of rows.ColumnTypes() and rows.Scan
+// without a real database.
+// The returned *sql.Rows (and its *sql.DB) are left open for the caller;
+// the process exits at the end of the test binary, so there is nothing
+// worth adding teardown plumbing for here.
+func openFakeRows(t testing.TB, columns []fakeColumn, rows [][]driver.Value) *sql.Rows {
+	t.Helper()
+
+	fakeFixtures.mu.Lock()
+	name := fmt.Sprintf("fixture%d", fakeFixtures.nextIdx)
+	fakeFixtures.nextIdx++
+	fakeFixtures.m[name] = &fakeFixture{columns: columns, rows: rows}
+	fakeFixtures.mu.Unlock()
+
+	db, err := sql.Open("sqltestfake", name)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+
+	result, err := db.Query("SELECT * FROM fake")
+	if err != nil {
+		t.Fatalf("db.Query: %v", err)
+	}
+	return result
+}
+
+// scanNextRow advances rows by one and scans it into the slice getRowSlice
+// builds for q, mirroring what convertCurrentRow does against a real
+// *sql.Rows.
+func scanNextRow(t testing.TB, q *querier, rows *sql.Rows) ([]interface{}, []string) {
+	t.Helper()
+
+	rowScanSlice, err := q.getRowSlice(rows)
+	if err != nil {
+		t.Fatalf("getRowSlice: %v", err)
+	}
+	columnNames, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("rows.Columns: %v", err)
+	}
+	if !rows.Next() {
+		t.Fatalf("expected a row, got none (err: %v)", rows.Err())
+	}
+	if err := rows.Scan(rowScanSlice...); err != nil {
+		t.Fatalf("rows.Scan: %v", err)
+	}
+	return rowScanSlice, columnNames
+}
+
+type fakeDriver struct{}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeFixtures.mu.Lock()
+	f, ok := fakeFixtures.m[name]
+	fakeFixtures.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("sqltestfake: no fixture registered as %q", name)
+	}
+	return &fakeConn{fixture: f}, nil
+}
+
+type fakeConn struct {
+	fixture *fakeFixture
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fakeConn: Prepare not supported, use Query")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fakeConn: transactions not supported")
+}
+
+// Query implements driver.Queryer so database/sql can run a query without
+// going through Prepare, which this fake driver doesn't support.
+func (c *fakeConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{fixture: c.fixture}, nil
+}
+
+var _ driver.Queryer = (*fakeConn)(nil)
+
+type fakeRows struct {
+	fixture *fakeFixture
+	idx     int
+}
+
+func (r *fakeRows) Columns() []string {
+	names := make([]string, len(r.fixture.columns))
+	for i, c := range r.fixture.columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.fixture.rows) {
+		return io.EOF
+	}
+	copy(dest, r.fixture.rows[r.idx])
+	r.idx++
+	return nil
+}
+
+// ColumnTypeDatabaseTypeName lets getRowSlice's scannerForColumnType pick a
+// scan destination the same way it would against a real driver.
+func (r *fakeRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.fixture.columns[index].dbType
+}
+
+var _ driver.RowsColumnTypeDatabaseTypeName = (*fakeRows)(nil)
@@ -0,0 +1,135 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/signalfx/signalfx-agent/internal/core/config"
+	"github.com/signalfx/signalfx-agent/internal/monitors"
+	"github.com/signalfx/signalfx-agent/internal/monitors/sql/presets"
+	"github.com/signalfx/signalfx-agent/internal/monitors/types"
+	"github.com/signalfx/signalfx-agent/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const monitorType = "sql"
+
+var logger = logrus.WithField("monitorType", monitorType)
+
+func init() {
+	monitors.Register(monitorType, func() interface{} { return &Monitor{} }, &Config{})
+}
+
+// Config for the sql monitor.
+type Config struct {
+	config.MonitorConfig `yaml:",inline" acceptsEndpoints:"false"`
+
+	// The Go sql driver to use to connect to the database, e.g. mysql,
+	// postgres, mssql, sqlite3.
+	DBDriver string `yaml:"dbDriver" validate:"required"`
+	// The connection string passed to the driver, in whatever format that
+	// driver expects.
+	ConnectionString string `yaml:"connectionString" validate:"required"`
+
+	// The SQL dialect of the target database. Setting this enables
+	// PresetGroups to pull in curated queries for that dialect. One of
+	// mysql, postgres, mssql.
+	Dialect string `yaml:"dialect"`
+	// Named groups of built-in queries to merge into Queries, e.g.
+	// [connections, waits, top_queries]. The groups available depend on
+	// Dialect; see the presets package.
+	PresetGroups []string `yaml:"presetGroups"`
+
+	// User-defined queries to run on every interval, in addition to any
+	// pulled in via PresetGroups.
+	Queries []Query `yaml:"queries"`
+
+	// How often to run the configured queries.
+	IntervalSeconds int `yaml:"intervalSeconds" default:"10"`
+
+	// If set, cursor values for queries with a Cursor configured are
+	// checkpointed to this file so that an agent restart resumes from
+	// where it left off instead of replaying history. If unset, cursors
+	// are kept in memory only.
+	CursorCheckpointPath string `yaml:"cursorCheckpointPath"`
+}
+
+// Monitor for sql.
+type Monitor struct {
+	Output types.Output
+	cancel context.CancelFunc
+}
+
+// Configure the monitor and start it.
+func (m *Monitor) Configure(conf *Config) error {
+	queries, err := resolveQueries(conf)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open(conf.DBDriver, conf.ConnectionString)
+	if err != nil {
+		return fmt.Errorf("could not open connection to %s database: %v", conf.DBDriver, err)
+	}
+
+	store := newCursorStore(conf.CursorCheckpointPath)
+	queriers := make([]*querier, len(queries))
+	for i := range queries {
+		queriers[i] = newQuerier(&queries[i], queries[i].LogQueries, store)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	utils.RunOnInterval(ctx, func() {
+		for _, q := range queriers {
+			if err := q.doQuery(ctx, db, m.Output); err != nil {
+				logger.WithError(err).Error("Could not run SQL query")
+			}
+		}
+	}, time.Duration(conf.IntervalSeconds)*time.Second)
+
+	return nil
+}
+
+// resolveQueries merges any preset query groups requested via Dialect and
+// PresetGroups in front of the user's own Queries.
+func resolveQueries(conf *Config) ([]Query, error) {
+	if len(conf.PresetGroups) == 0 {
+		return conf.Queries, nil
+	}
+	if conf.Dialect == "" {
+		return nil, fmt.Errorf("sql monitor: presetGroups %v given but dialect is not set", conf.PresetGroups)
+	}
+
+	presetQueries, err := presets.Load(conf.Dialect, conf.PresetGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]Query, 0, len(presetQueries)+len(conf.Queries))
+	for _, pq := range presetQueries {
+		metrics := make([]Metric, len(pq.Metrics))
+		for i, pm := range pq.Metrics {
+			metrics[i] = Metric{
+				MetricName:       pm.MetricName,
+				ValueColumn:      pm.ValueColumn,
+				DimensionColumns: pm.DimensionColumns,
+				IsCumulative:     pm.IsCumulative,
+			}
+		}
+		queries = append(queries, Query{Query: pq.Query, Metrics: metrics})
+	}
+	queries = append(queries, conf.Queries...)
+
+	return queries, nil
+}
+
+// Shutdown stops the monitor.
+func (m *Monitor) Shutdown() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
@@ -0,0 +1,31 @@
+package presets
+
+const mssqlBundleYAML = `
+groups:
+  waits:
+    - query: "SELECT wait_type, waiting_tasks_count, wait_time_ms FROM sys.dm_os_wait_stats"
+      metrics:
+        - metricName: mssql.waiting_tasks_count
+          valueColumn: waiting_tasks_count
+          dimensionColumns: [wait_type]
+          isCumulative: true
+        - metricName: mssql.wait_time_ms
+          valueColumn: wait_time_ms
+          dimensionColumns: [wait_type]
+          isCumulative: true
+
+  top_queries:
+    - query: >-
+        SELECT TOP 20 qs.query_hash, qs.execution_count, qs.total_worker_time
+        FROM sys.dm_exec_query_stats qs
+        ORDER BY qs.total_worker_time DESC
+      metrics:
+        - metricName: mssql.statement.execution_count
+          valueColumn: execution_count
+          dimensionColumns: [query_hash]
+          isCumulative: true
+        - metricName: mssql.statement.total_worker_time
+          valueColumn: total_worker_time
+          dimensionColumns: [query_hash]
+          isCumulative: true
+`
@@ -0,0 +1,34 @@
+package presets
+
+const mysqlBundleYAML = `
+groups:
+  connections:
+    - query: "SHOW GLOBAL STATUS LIKE 'Threads_connected'"
+      metrics:
+        - metricName: mysql.threads_connected
+          valueColumn: Value
+    - query: "SHOW GLOBAL STATUS LIKE 'Threads_running'"
+      metrics:
+        - metricName: mysql.threads_running
+          valueColumn: Value
+    - query: "SHOW GLOBAL STATUS LIKE 'Max_used_connections'"
+      metrics:
+        - metricName: mysql.max_used_connections
+          valueColumn: Value
+
+  top_queries:
+    - query: >-
+        SELECT digest, count_star, sum_timer_wait
+        FROM performance_schema.events_statements_summary_by_digest
+        ORDER BY sum_timer_wait DESC
+        LIMIT 20
+      metrics:
+        - metricName: mysql.statement.count
+          valueColumn: count_star
+          dimensionColumns: [digest]
+          isCumulative: true
+        - metricName: mysql.statement.latency_ns
+          valueColumn: sum_timer_wait
+          dimensionColumns: [digest]
+          isCumulative: true
+`
@@ -0,0 +1,51 @@
+package presets
+
+const postgresBundleYAML = `
+groups:
+  connections:
+    - query: "SELECT datname, numbackends, xact_commit, xact_rollback FROM pg_stat_database"
+      metrics:
+        - metricName: postgres.connections
+          valueColumn: numbackends
+          dimensionColumns: [datname]
+        - metricName: postgres.commits
+          valueColumn: xact_commit
+          dimensionColumns: [datname]
+          isCumulative: true
+        - metricName: postgres.rollbacks
+          valueColumn: xact_rollback
+          dimensionColumns: [datname]
+          isCumulative: true
+
+  waits:
+    - query: "SELECT checkpoints_timed, checkpoints_req, buffers_checkpoint, buffers_clean FROM pg_stat_bgwriter"
+      metrics:
+        - metricName: postgres.checkpoints_timed
+          valueColumn: checkpoints_timed
+          isCumulative: true
+        - metricName: postgres.checkpoints_requested
+          valueColumn: checkpoints_req
+          isCumulative: true
+        - metricName: postgres.buffers_checkpoint
+          valueColumn: buffers_checkpoint
+          isCumulative: true
+        - metricName: postgres.buffers_clean
+          valueColumn: buffers_clean
+          isCumulative: true
+
+  top_queries:
+    - query: >-
+        SELECT query, calls, total_time, rows
+        FROM pg_stat_statements
+        ORDER BY total_time DESC
+        LIMIT 20
+      metrics:
+        - metricName: postgres.statement.calls
+          valueColumn: calls
+          dimensionColumns: [query]
+          isCumulative: true
+        - metricName: postgres.statement.total_time_ms
+          valueColumn: total_time
+          dimensionColumns: [query]
+          isCumulative: true
+`
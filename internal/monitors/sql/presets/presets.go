@@ -0,0 +1,67 @@
+// Package presets holds curated, dialect-specific bundles of SQL monitor
+// queries for common databases, so users can get useful metrics without
+// hand-writing SQL. Bundles are grouped under named preset groups (e.g.
+// "connections", "waits") that a user opts into via the monitor's
+// PresetGroups config option.
+//
+// This package intentionally does not depend on the sql monitor package
+// itself; it defines its own Query/Metric shapes (holding a subset of the
+// monitor's fields: no params, events, cursor or timestamp columns) and the
+// monitor converts them after loading.
+package presets
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Metric is the preset equivalent of the sql monitor's Metric config.
+type Metric struct {
+	MetricName       string   `yaml:"metricName"`
+	ValueColumn      string   `yaml:"valueColumn"`
+	DimensionColumns []string `yaml:"dimensionColumns"`
+	IsCumulative     bool     `yaml:"isCumulative"`
+}
+
+// Query is the preset equivalent of the sql monitor's Query config.
+type Query struct {
+	Query   string   `yaml:"query"`
+	Metrics []Metric `yaml:"metrics"`
+}
+
+type bundle struct {
+	Groups map[string][]Query `yaml:"groups"`
+}
+
+// bundlesByDialect maps a Dialect config value to its raw YAML bundle.
+var bundlesByDialect = map[string]string{
+	"mysql":    mysqlBundleYAML,
+	"postgres": postgresBundleYAML,
+	"mssql":    mssqlBundleYAML,
+}
+
+// Load parses the given dialect's bundle and returns the merged list of
+// queries for the requested preset groups, in the order the groups were
+// given.
+func Load(dialect string, groups []string) ([]Query, error) {
+	raw, ok := bundlesByDialect[dialect]
+	if !ok {
+		return nil, fmt.Errorf("sql monitor: no query presets defined for dialect %q", dialect)
+	}
+
+	var b bundle
+	if err := yaml.Unmarshal([]byte(raw), &b); err != nil {
+		return nil, fmt.Errorf("sql monitor: could not parse preset bundle for dialect %q: %v", dialect, err)
+	}
+
+	var out []Query
+	for _, group := range groups {
+		qs, ok := b.Groups[group]
+		if !ok {
+			return nil, fmt.Errorf("sql monitor: dialect %q has no preset group %q", dialect, group)
+		}
+		out = append(out, qs...)
+	}
+	return out, nil
+}
@@ -4,153 +4,415 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/signalfx/golib/datapoint"
+	"github.com/signalfx/golib/event"
 	"github.com/signalfx/signalfx-agent/internal/monitors/types"
 	"github.com/sirupsen/logrus"
 )
 
-type querier struct {
-	query                     *Query
-	valueColumnNamesToMetrics map[string]*Metric
-	metricToIndex             map[*Metric]int
-	dimensionColumnSets       []map[string]bool
-	datapoints                []*datapoint.Datapoint
-	rowSliceCached            []interface{}
-	logger                    logrus.FieldLogger
-	logQueries                bool
+// columnDispatch records, for a single column position in a query's result
+// set, every metric/event/cursor that column feeds. It is computed once per
+// query (the first time a row is seen) instead of re-deriving it by column
+// name on every row, which matters for wide SELECTs with dozens of metrics.
+type columnDispatch struct {
+	valueMetric int   // index into query.Metrics, or -1
+	dimMetrics  []int // metric indices this column is a dimension for
+	tsMetrics   []int // metric indices this column is the TimestampColumn for
+
+	eventDims  []int // event spec indices this column is a dimension for
+	eventProps []int // event spec indices this column is a property for
+	eventTs    []int // event spec indices this column is the TimestampColumn for
+
+	isCursor bool
 }
 
-func newQuerier(query *Query, logQueries bool) *querier {
-	valueColumnNamesToMetrics := map[string]*Metric{}
-	metricToIndex := map[*Metric]int{}
+type querier struct {
+	query *Query
 
-	for i, m := range query.Metrics {
-		valueColumnNamesToMetrics[strings.ToLower(m.ValueColumn)] = &query.Metrics[i]
-		metricToIndex[&query.Metrics[i]] = i
-	}
+	// columnDispatch and rowSliceCached are both indexed by column
+	// position and computed together the first time a row is seen.
+	columnDispatch []columnDispatch
+	rowSliceCached []interface{}
 
-	dimensionColumnSets := make([]map[string]bool, len(query.Metrics))
-	for i := range dimensionColumnSets {
-		dimensionColumnSets[i] = map[string]bool{}
-	}
+	// cursor is non-nil if this query runs incrementally.
+	cursor *cursorState
 
-	// Make a set of cloneable datapoints that already have metric name and
-	// type set since it never changes with the same metric config.
-	dps := make([]*datapoint.Datapoint, len(query.Metrics))
-	for i, m := range query.Metrics {
-		typ := datapoint.Gauge
-		if m.IsCumulative {
-			typ = datapoint.Counter
-		}
-		dps[i] = datapoint.New(m.MetricName, nil, nil, typ, time.Time{})
+	logger     logrus.FieldLogger
+	logQueries bool
+}
 
-		for _, dim := range m.DimensionColumns {
-			dimensionColumnSets[i][strings.ToLower(dim)] = true
-		}
+func newQuerier(query *Query, logQueries bool, cursorStore *cursorStore) *querier {
+	var cursor *cursorState
+	if query.Cursor != nil {
+		cursor = newCursorState(query.Cursor, query.Query, cursorStore)
 	}
 
 	return &querier{
-		query: query,
-		// Preallocate the slice and reuse it since it will only be used
-		// serially.
-		datapoints:                dps,
-		valueColumnNamesToMetrics: valueColumnNamesToMetrics,
-		metricToIndex:             metricToIndex,
-		dimensionColumnSets:       dimensionColumnSets,
-		logger:                    logger.WithField("statement", query.Query),
-		logQueries:                logQueries,
+		query:      query,
+		cursor:     cursor,
+		logger:     logger.WithField("statement", query.Query),
+		logQueries: logQueries,
 	}
 }
 
+// dimMapPool holds scratch map[string]string instances used while
+// accumulating a row's dimensions, so that the working maps are reused
+// across rows instead of allocated for each one. Each emitted datapoint or
+// event still gets its own dimension map copied from the scratch map, and
+// event property maps are not pooled.
+var dimMapPool = sync.Pool{
+	New: func() interface{} { return map[string]string{} },
+}
+
+func getScratchDimMap() map[string]string {
+	return dimMapPool.Get().(map[string]string)
+}
+
+func putScratchDimMap(m map[string]string) {
+	for k := range m {
+		delete(m, k)
+	}
+	dimMapPool.Put(m)
+}
+
 func (q *querier) doQuery(ctx context.Context, database *sql.DB, output types.Output) error {
-	rows, err := database.QueryContext(ctx, q.query.Query, q.query.Params...)
+	rows, err := database.QueryContext(ctx, q.query.Query, q.params()...)
 	if err != nil {
 		return fmt.Errorf("error executing statement %s: %v", q.query.Query, err)
 	}
 
 	for rows.Next() {
-		// We can just reuse the rowSlice for every row since it will reset
-		// itself.
-		dps, err := q.convertCurrentRowToDatapoint(rows)
+		dps, evs, cursorVal, err := q.convertCurrentRow(rows)
 		if err != nil {
 			return err
 		}
 		for i := range dps {
-			if dps[i].Value == nil {
-				q.logger.Warnf("Metric %s's value column '%s' did not correspond to a value",
-					q.query.Metrics[i].MetricName, q.query.Metrics[i].ValueColumn)
-				continue
-			}
 			output.SendDatapoint(dps[i])
 		}
+		for i := range evs {
+			output.SendEvent(evs[i])
+		}
+		if q.cursor != nil {
+			q.cursor.advance(cursorVal)
+		}
 	}
+
+	// Persist the cursor checkpoint at most once per doQuery call, not once
+	// per row, so a backfill or a busy audit table doesn't serialize this
+	// query behind a blocking disk write for every row it emits.
+	if q.cursor != nil {
+		q.cursor.store.flush()
+	}
+
 	return rows.Close()
 }
 
-func (q *querier) convertCurrentRowToDatapoint(rows *sql.Rows) ([]*datapoint.Datapoint, error) {
+// params returns the Params for this query's next run, substituting the
+// current cursor value for any positional CursorPlaceholder entries. See
+// CursorPlaceholder's doc comment: there is no named-parameter equivalent,
+// so a CursorPlaceholder value nested inside anything other than a plain
+// Params entry (e.g. a driver-specific named-arg wrapper) passes through
+// unsubstituted.
+func (q *querier) params() []interface{} {
+	if q.cursor == nil {
+		return q.query.Params
+	}
+
+	params := make([]interface{}, len(q.query.Params))
+	copy(params, q.query.Params)
+	for i, p := range params {
+		if s, ok := p.(string); ok && s == CursorPlaceholder {
+			params[i] = q.cursor.param()
+		}
+	}
+	return params
+}
+
+func (q *querier) convertCurrentRow(rows *sql.Rows) ([]*datapoint.Datapoint, []*event.Event, string, error) {
 	rowScanSlice, err := q.getRowSlice(rows)
 	if err != nil {
-		return nil, err
+		return nil, nil, "", err
 	}
 
 	columnNames, err := rows.Columns()
 	if err != nil {
-		return nil, err
+		return nil, nil, "", err
 	}
 
 	if err := rows.Scan(rowScanSlice...); err != nil {
-		return nil, err
+		return nil, nil, "", err
 	}
 	if q.logQueries {
 		q.logger.Info("Got results %s", spew.Sdump(rowScanSlice))
 	}
 
-	// Clone all datapoints before updating them
-	for i := range q.datapoints {
-		dpCopy := *q.datapoints[i]
-		q.datapoints[i] = &dpCopy
-		q.datapoints[i].Dimensions = map[string]string{}
-		q.datapoints[i].Meta = map[interface{}]interface{}{}
-	}
+	return q.processRow(rowScanSlice, columnNames)
+}
+
+// processRow turns one already-scanned row into datapoints and events. It is
+// split out from convertCurrentRow so it can be exercised directly (and
+// benchmarked) without a live *sql.Rows.
+func (q *querier) processRow(rowScanSlice []interface{}, columnNames []string) ([]*datapoint.Datapoint, []*event.Event, string, error) {
+	numMetrics := len(q.query.Metrics)
+	numEvents := len(q.query.Events)
+
+	metricValues := make([]float64, numMetrics)
+	metricValueSet := make([]bool, numMetrics)
+	metricDims := make([]map[string]string, numMetrics)
+	metricTimestamps := make([]time.Time, numMetrics)
+
+	eventDims := make([]map[string]string, numEvents)
+	eventProps := make([]map[string]interface{}, numEvents)
+	eventTimestamps := make([]time.Time, numEvents)
+
+	var cursorVal string
 
 	for i := range rowScanSlice {
-		switch v := rowScanSlice[i].(type) {
-		case *sql.NullFloat64:
-			if !v.Valid {
-				return nil, fmt.Errorf("column %d is null", i)
+		d := q.columnDispatch[i]
+
+		if d.isCursor {
+			cursorVal = rawValueFromScanned(rowScanSlice[i])
+		}
+
+		for _, j := range d.tsMetrics {
+			if ts, ok := timeValueFromScanned(rowScanSlice[i], q.query.Metrics[j].TimestampLayout); ok {
+				metricTimestamps[j] = ts
 			}
+		}
+		for _, k := range d.eventTs {
+			if ts, ok := timeValueFromScanned(rowScanSlice[i], q.query.Events[k].TimestampLayout); ok {
+				eventTimestamps[k] = ts
+			}
+		}
 
-			metric, ok := q.valueColumnNamesToMetrics[strings.ToLower(columnNames[i])]
-			if !ok || metric == nil {
-				// This is a logical error in the code, not user input error
-				panic("valueColumn was not properly mapped to metric")
+		if d.valueMetric >= 0 {
+			if val, valid := numericValueFromScanned(rowScanSlice[i]); valid {
+				metricValues[d.valueMetric] = val
+				metricValueSet[d.valueMetric] = true
 			}
+		}
 
-			dp := q.datapoints[q.metricToIndex[metric]]
-			dp.Value = datapoint.NewFloatValue(v.Float64)
+		if len(d.dimMetrics) == 0 && len(d.eventDims) == 0 && len(d.eventProps) == 0 {
+			continue
+		}
 
-		case *sql.NullString:
-			dimVal := v.String
-			if !v.Valid {
-				// Make sure the value gets properly blanked out since we are
-				// reusing rowScanSlice between rows/queries.
-				dimVal = ""
+		if len(d.dimMetrics) > 0 || len(d.eventDims) > 0 {
+			dimVal := stringValueFromScanned(rowScanSlice[i])
+			for _, j := range d.dimMetrics {
+				if metricDims[j] == nil {
+					metricDims[j] = getScratchDimMap()
+				}
+				metricDims[j][columnNames[i]] = dimVal
+			}
+			for _, k := range d.eventDims {
+				if eventDims[k] == nil {
+					eventDims[k] = getScratchDimMap()
+				}
+				eventDims[k][columnNames[i]] = dimVal
 			}
-			for j := range q.query.Metrics {
-				if !q.dimensionColumnSets[j][strings.ToLower(columnNames[i])] {
-					continue
+		}
+
+		if len(d.eventProps) > 0 {
+			if propVal := nativeValueFromScanned(rowScanSlice[i]); propVal != nil {
+				for _, k := range d.eventProps {
+					if eventProps[k] == nil {
+						eventProps[k] = map[string]interface{}{}
+					}
+					eventProps[k][columnNames[i]] = propVal
 				}
+			}
+		}
+	}
 
-				q.datapoints[j].Dimensions[columnNames[i]] = dimVal
+	// Only build a datapoint for metrics that actually got a value this
+	// row, instead of cloning every configured metric on every row.
+	dps := make([]*datapoint.Datapoint, 0, numMetrics)
+	for j, m := range q.query.Metrics {
+		if !metricValueSet[j] {
+			if metricDims[j] != nil {
+				putScratchDimMap(metricDims[j])
 			}
+			continue
+		}
+
+		typ := datapoint.Gauge
+		if m.IsCumulative {
+			typ = datapoint.Counter
 		}
+
+		dims := map[string]string{}
+		if metricDims[j] != nil {
+			for k, v := range metricDims[j] {
+				dims[k] = v
+			}
+			putScratchDimMap(metricDims[j])
+		}
+
+		dps = append(dps, datapoint.New(m.MetricName, dims, datapoint.NewFloatValue(metricValues[j]), typ, metricTimestamps[j]))
 	}
 
-	return q.datapoints, nil
+	events := make([]*event.Event, numEvents)
+	for k, spec := range q.query.Events {
+		dims := map[string]string{}
+		if eventDims[k] != nil {
+			for dk, dv := range eventDims[k] {
+				dims[dk] = dv
+			}
+			putScratchDimMap(eventDims[k])
+		}
+		props := eventProps[k]
+		if props == nil {
+			props = map[string]interface{}{}
+		}
+		events[k] = event.NewEvent(spec.EventType, dims, event.AGENT, props, eventTimestamps[k])
+	}
+
+	return dps, events, cursorVal, nil
+}
+
+// rawValueFromScanned renders a scanned Cursor.Column value in a form
+// cursorState can compare and persist: an integer or RFC3339 timestamp
+// string when possible, otherwise the column's plain string value.
+func rawValueFromScanned(v interface{}) string {
+	switch val := v.(type) {
+	case *sql.NullInt64:
+		if !val.Valid {
+			return ""
+		}
+		return strconv.FormatInt(val.Int64, 10)
+	case *sql.NullTime:
+		if !val.Valid {
+			return ""
+		}
+		return val.Time.Format(time.RFC3339Nano)
+	case *sql.NullString:
+		if !val.Valid {
+			return ""
+		}
+		return val.String
+	default:
+		return stringValueFromScanned(v)
+	}
+}
+
+// numericValueFromScanned pulls a float64 out of a scanned column, whatever
+// concrete null type getRowSlice picked for it. The bool return is false if
+// the column was NULL or isn't a type that can represent a datapoint value.
+func numericValueFromScanned(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case *sql.NullFloat64:
+		return val.Float64, val.Valid
+	case *sql.NullInt64:
+		return float64(val.Int64), val.Valid
+	case *sql.NullBool:
+		if !val.Valid {
+			return 0, false
+		}
+		if val.Bool {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// timeValueFromScanned extracts a datapoint timestamp from a scanned
+// TimestampColumn. If the driver scanned the column as a native time, layout
+// is ignored; otherwise the string value is parsed using layout, which must
+// be a valid Go time layout.
+func timeValueFromScanned(v interface{}, layout string) (time.Time, bool) {
+	switch val := v.(type) {
+	case *sql.NullTime:
+		return val.Time, val.Valid
+	case *sql.NullString:
+		if !val.Valid || layout == "" {
+			return time.Time{}, false
+		}
+		t, err := time.Parse(layout, val.String)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// nativeValueFromScanned returns a scanned column's value as the most
+// natural Go type for it, for use as an event property, which (unlike a
+// dimension) isn't restricted to strings. Returns nil for NULL columns.
+func nativeValueFromScanned(v interface{}) interface{} {
+	switch val := v.(type) {
+	case *sql.NullFloat64:
+		if !val.Valid {
+			return nil
+		}
+		return val.Float64
+	case *sql.NullInt64:
+		if !val.Valid {
+			return nil
+		}
+		return val.Int64
+	case *sql.NullBool:
+		if !val.Valid {
+			return nil
+		}
+		return val.Bool
+	case *sql.NullTime:
+		if !val.Valid {
+			return nil
+		}
+		return val.Time
+	case *sql.NullString:
+		if !val.Valid {
+			return nil
+		}
+		return val.String
+	default:
+		return nil
+	}
+}
+
+// stringValueFromScanned formats a scanned column as a dimension value,
+// regardless of which concrete null type getRowSlice picked for it.
+func stringValueFromScanned(v interface{}) string {
+	switch val := v.(type) {
+	case *sql.NullString:
+		if !val.Valid {
+			return ""
+		}
+		return val.String
+	case *sql.NullFloat64:
+		if !val.Valid {
+			return ""
+		}
+		return strconv.FormatFloat(val.Float64, 'f', -1, 64)
+	case *sql.NullInt64:
+		if !val.Valid {
+			return ""
+		}
+		return fmt.Sprintf("%d", val.Int64)
+	case *sql.NullBool:
+		if !val.Valid {
+			return ""
+		}
+		return fmt.Sprintf("%v", val.Bool)
+	case *sql.NullTime:
+		if !val.Valid {
+			return ""
+		}
+		return fmt.Sprintf("%v", val.Time)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
 }
 
 func (q *querier) getRowSlice(rows *sql.Rows) ([]interface{}, error) {
@@ -164,30 +426,75 @@ func (q *querier) getRowSlice(rows *sql.Rows) ([]interface{}, error) {
 	}
 
 	dimColsSeen := map[string]bool{}
+	eventColsSeen := map[string]bool{}
+	cursorColSeen := false
+
 	rowSlice := make([]interface{}, len(cts))
-OUTER:
+	dispatch := make([]columnDispatch, len(cts))
+
 	for i, ct := range cts {
-		for _, metric := range q.query.Metrics {
+		ctName := strings.ToLower(ct.Name())
+		d := columnDispatch{valueMetric: -1}
 
-			if strings.ToLower(ct.Name()) == strings.ToLower(metric.ValueColumn) {
-				// Values are always numeric
-				rowSlice[i] = &sql.NullFloat64{}
-				// Can't also be a dimension column or value in another metric
-				continue OUTER
+		if q.cursor != nil && q.cursor.column == ctName {
+			d.isCursor = true
+			cursorColSeen = true
+		}
+
+		isValueCol := false
+		for j, m := range q.query.Metrics {
+			if strings.ToLower(m.ValueColumn) == ctName {
+				d.valueMetric = j
+				isValueCol = true
+			}
+			if strings.ToLower(m.TimestampColumn) == ctName && m.TimestampColumn != "" {
+				d.tsMetrics = append(d.tsMetrics, j)
 			}
+			for _, dim := range m.DimensionColumns {
+				if strings.ToLower(dim) == ctName {
+					dimColsSeen[dim] = true
+					d.dimMetrics = append(d.dimMetrics, j)
+				}
+			}
+		}
 
-			for _, colName := range metric.DimensionColumns {
-				if strings.ToLower(ct.Name()) == strings.ToLower(colName) {
-					dimColsSeen[colName] = true
-					rowSlice[i] = &sql.NullString{}
-					// Cannot also be a value column if dimension
-					continue OUTER
+		for k, spec := range q.query.Events {
+			if strings.ToLower(spec.TimestampColumn) == ctName && spec.TimestampColumn != "" {
+				d.eventTs = append(d.eventTs, k)
+			}
+			for _, dim := range spec.DimensionColumns {
+				if strings.ToLower(dim) == ctName {
+					eventColsSeen[strings.ToLower(dim)] = true
+					d.eventDims = append(d.eventDims, k)
 				}
 			}
+			for _, prop := range spec.PropertyColumns {
+				if strings.ToLower(prop) == ctName {
+					eventColsSeen[strings.ToLower(prop)] = true
+					d.eventProps = append(d.eventProps, k)
+				}
+			}
+		}
+
+		needsScalarScanner := isValueCol || len(d.dimMetrics) > 0 || len(d.tsMetrics) > 0 ||
+			len(d.eventDims) > 0 || len(d.eventProps) > 0 || len(d.eventTs) > 0 || d.isCursor
 
+		switch {
+		case isValueCol:
+			rowSlice[i] = scannerForColumnType(ct, true)
+		case needsScalarScanner:
+			rowSlice[i] = scannerForColumnType(ct, false)
+		default:
+			// This column is unused in generating metrics/events so just
+			// make it a string.
+			rowSlice[i] = &sql.NullString{}
 		}
-		// This column is unused in generating metrics so just make it a string
-		rowSlice[i] = &sql.NullString{}
+
+		dispatch[i] = d
+	}
+
+	if q.cursor != nil && !cursorColSeen {
+		return nil, fmt.Errorf("cursor column '%s' does not exist", q.query.Cursor.Column)
 	}
 
 	for _, metric := range q.query.Metrics {
@@ -198,6 +505,62 @@ OUTER:
 		}
 	}
 
+	for _, spec := range q.query.Events {
+		for _, colName := range append(append([]string{}, spec.DimensionColumns...), spec.PropertyColumns...) {
+			if !eventColsSeen[strings.ToLower(colName)] && !dimColsSeen[colName] {
+				if _, ok := valueColumnOwner(q.query.Metrics, colName); !ok {
+					return nil, fmt.Errorf("event column '%s' does not exist", colName)
+				}
+			}
+		}
+	}
+
 	q.rowSliceCached = rowSlice
+	q.columnDispatch = dispatch
 	return rowSlice, nil
 }
+
+func valueColumnOwner(metrics []Metric, colName string) (Metric, bool) {
+	for _, m := range metrics {
+		if strings.ToLower(m.ValueColumn) == strings.ToLower(colName) {
+			return m, true
+		}
+	}
+	return Metric{}, false
+}
+
+// scannerForColumnType picks a scan destination appropriate for the
+// database driver's reported type of the column, so that users don't have
+// to CAST every INT/BIGINT/BOOL/TIMESTAMP column to a string or float in
+// their SQL. isValueColumn columns are restricted to the numeric types
+// datapoint values support; everything else falls back to a string.
+func scannerForColumnType(ct *sql.ColumnType, isValueColumn bool) interface{} {
+	switch strings.ToUpper(ct.DatabaseTypeName()) {
+	case "BOOL", "BOOLEAN":
+		return &sql.NullBool{}
+	case "INT", "INT2", "INT4", "INT8", "INTEGER", "SMALLINT", "MEDIUMINT", "BIGINT", "TINYINT", "SERIAL", "BIGSERIAL":
+		return &sql.NullInt64{}
+	case "TIMESTAMP", "TIMESTAMPTZ", "DATE", "DATETIME", "TIME":
+		if !isValueColumn {
+			return &sql.NullTime{}
+		}
+	}
+
+	if scanType := ct.ScanType(); scanType != nil {
+		switch scanType.Kind() {
+		case reflect.Bool:
+			return &sql.NullBool{}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return &sql.NullInt64{}
+		case reflect.Float32, reflect.Float64:
+			return &sql.NullFloat64{}
+		}
+	}
+
+	if isValueColumn {
+		// Values are numeric by default.
+		return &sql.NullFloat64{}
+	}
+	return &sql.NullString{}
+}
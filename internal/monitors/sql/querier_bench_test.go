@@ -0,0 +1,108 @@
+package sql
+
+import (
+	"database/sql/driver"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/signalfx/golib/datapoint"
+)
+
+// legacyCloneAllDatapoints reproduces the pre-optimization convertCurrentRow
+// behavior: every configured metric's datapoint is cloned on every row, even
+// when most of its columns are NULL in a wide result set. It is kept here
+// only to give BenchmarkProcessRow something to compare the dispatch-based
+// implementation against.
+func legacyCloneAllDatapoints(metrics []Metric, templates []*datapoint.Datapoint, rowScanSlice []interface{}, columnNames []string) []*datapoint.Datapoint {
+	dps := make([]*datapoint.Datapoint, len(templates))
+	for i := range templates {
+		dpCopy := *templates[i]
+		dps[i] = &dpCopy
+		dps[i].Dimensions = map[string]string{}
+	}
+
+	valueColumnIndex := map[string]int{}
+	for i, m := range metrics {
+		valueColumnIndex[m.ValueColumn] = i
+	}
+
+	for i, colName := range columnNames {
+		if j, ok := valueColumnIndex[colName]; ok {
+			val, valid := numericValueFromScanned(rowScanSlice[i])
+			if valid {
+				dps[j].Value = datapoint.NewFloatValue(val)
+			}
+			continue
+		}
+
+		dimVal := stringValueFromScanned(rowScanSlice[i])
+		for j := range metrics {
+			for _, dim := range metrics[j].DimensionColumns {
+				if dim == colName {
+					dps[j].Dimensions[colName] = dimVal
+				}
+			}
+		}
+	}
+
+	return dps
+}
+
+// benchRow builds a synthetic wide row of numMetrics value columns, where
+// only 1 in 10 are non-null, like a real metrics table where most columns
+// are irrelevant to any one row. The row is scanned through a fake driver
+// so q.columnDispatch is built by the real getRowSlice, not a hand-rolled
+// copy of its matching logic.
+func benchRow(b *testing.B, numMetrics int) ([]Metric, []*datapoint.Datapoint, *querier, []interface{}, []string) {
+	metrics := make([]Metric, numMetrics)
+	templates := make([]*datapoint.Datapoint, numMetrics)
+	columns := make([]fakeColumn, numMetrics)
+	row := make([]driver.Value, numMetrics)
+
+	for i := range metrics {
+		col := "col_" + strconv.Itoa(i)
+		metrics[i] = Metric{MetricName: "metric_" + strconv.Itoa(i), ValueColumn: col}
+		templates[i] = datapoint.New(metrics[i].MetricName, nil, nil, datapoint.Gauge, time.Time{})
+		columns[i] = fakeColumn{name: col, dbType: "FLOAT"}
+
+		if i%10 == 0 {
+			row[i] = float64(i)
+		}
+	}
+
+	q := &querier{query: &Query{Metrics: metrics}}
+	rows := openFakeRows(b, columns, [][]driver.Value{row})
+	rowScanSlice, columnNames := scanNextRow(b, q, rows)
+
+	return metrics, templates, q, rowScanSlice, columnNames
+}
+
+const benchNumMetrics = 50
+const benchNumRows = 10000
+
+func BenchmarkProcessRow_Dispatch(b *testing.B) {
+	_, _, q, rowScanSlice, columnNames := benchRow(b, benchNumMetrics)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for r := 0; r < benchNumRows; r++ {
+			if _, _, _, err := q.processRow(rowScanSlice, columnNames); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkProcessRow_CloneAll(b *testing.B) {
+	metrics, templates, _, rowScanSlice, columnNames := benchRow(b, benchNumMetrics)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for r := 0; r < benchNumRows; r++ {
+			legacyCloneAllDatapoints(metrics, templates, rowScanSlice, columnNames)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package sql
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+// TestProcessRow_ColumnIsDimensionAndEventProperty covers a column that is
+// both a metric dimension and an event property, which exercises the
+// overlapping dispatch lists (dimMetrics and eventProps) getRowSlice builds
+// for the same column position.
+func TestProcessRow_ColumnIsDimensionAndEventProperty(t *testing.T) {
+	query := &Query{
+		Metrics: []Metric{
+			{MetricName: "requests", ValueColumn: "count", DimensionColumns: []string{"host"}},
+		},
+		Events: []EventSpec{
+			{EventType: "deploy", PropertyColumns: []string{"host"}},
+		},
+	}
+	rows := openFakeRows(t,
+		[]fakeColumn{{"count", "FLOAT"}, {"host", "VARCHAR"}},
+		[][]driver.Value{{float64(42), "web-1"}},
+	)
+	q := &querier{query: query}
+	rowScanSlice, columnNames := scanNextRow(t, q, rows)
+
+	dps, evs, _, err := q.processRow(rowScanSlice, columnNames)
+	if err != nil {
+		t.Fatalf("processRow returned error: %v", err)
+	}
+
+	if len(dps) != 1 || dps[0].Dimensions["host"] != "web-1" {
+		t.Fatalf("expected datapoint with host dimension web-1, got %+v", dps)
+	}
+	if len(evs) != 1 || evs[0].Properties["host"] != "web-1" {
+		t.Fatalf("expected event with host property web-1, got %+v", evs)
+	}
+}
+
+// TestProcessRow_NullValueColumnInWideRow ensures a NULL value column does
+// not produce a datapoint, even among other non-null columns in the row.
+func TestProcessRow_NullValueColumnInWideRow(t *testing.T) {
+	query := &Query{
+		Metrics: []Metric{
+			{MetricName: "present", ValueColumn: "a"},
+			{MetricName: "absent", ValueColumn: "b"},
+		},
+	}
+	rows := openFakeRows(t,
+		[]fakeColumn{{"a", "FLOAT"}, {"b", "FLOAT"}},
+		[][]driver.Value{{float64(1), nil}},
+	)
+	q := &querier{query: query}
+	rowScanSlice, columnNames := scanNextRow(t, q, rows)
+
+	dps, _, _, err := q.processRow(rowScanSlice, columnNames)
+	if err != nil {
+		t.Fatalf("processRow returned error: %v", err)
+	}
+
+	if len(dps) != 1 || dps[0].Metric != "present" {
+		t.Fatalf("expected exactly one datapoint for the non-null column, got %+v", dps)
+	}
+}
+
+// TestProcessRow_TimestampColumn_Layout covers a TimestampColumn scanned as
+// a string and parsed via TimestampLayout.
+func TestProcessRow_TimestampColumn_Layout(t *testing.T) {
+	query := &Query{
+		Metrics: []Metric{
+			{MetricName: "m", ValueColumn: "v", TimestampColumn: "ts", TimestampLayout: "2006-01-02 15:04:05"},
+		},
+	}
+	rows := openFakeRows(t,
+		[]fakeColumn{{"v", "FLOAT"}, {"ts", "VARCHAR"}},
+		[][]driver.Value{{float64(1), "2020-01-02 03:04:05"}},
+	)
+	q := &querier{query: query}
+	rowScanSlice, columnNames := scanNextRow(t, q, rows)
+
+	dps, _, _, err := q.processRow(rowScanSlice, columnNames)
+	if err != nil {
+		t.Fatalf("processRow returned error: %v", err)
+	}
+
+	want := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if len(dps) != 1 || !dps[0].Timestamp.Equal(want) {
+		t.Fatalf("expected timestamp %v, got %+v", want, dps)
+	}
+}
+
+// TestProcessRow_TimestampColumn_Native covers a TimestampColumn scanned as
+// a native time type, where TimestampLayout is ignored.
+func TestProcessRow_TimestampColumn_Native(t *testing.T) {
+	query := &Query{
+		Metrics: []Metric{
+			{MetricName: "m", ValueColumn: "v", TimestampColumn: "ts"},
+		},
+	}
+	want := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := openFakeRows(t,
+		[]fakeColumn{{"v", "FLOAT"}, {"ts", "TIMESTAMP"}},
+		[][]driver.Value{{float64(1), want}},
+	)
+	q := &querier{query: query}
+	rowScanSlice, columnNames := scanNextRow(t, q, rows)
+
+	dps, _, _, err := q.processRow(rowScanSlice, columnNames)
+	if err != nil {
+		t.Fatalf("processRow returned error: %v", err)
+	}
+
+	if len(dps) != 1 || !dps[0].Timestamp.Equal(want) {
+		t.Fatalf("expected timestamp %v, got %+v", want, dps)
+	}
+}
+
+// TestProcessRow_CursorValue covers extraction of the cursor column's value
+// from a row, independent of any metric/event columns.
+func TestProcessRow_CursorValue(t *testing.T) {
+	query := &Query{
+		Cursor: &Cursor{Column: "id"},
+	}
+	rows := openFakeRows(t,
+		[]fakeColumn{{"id", "BIGINT"}},
+		[][]driver.Value{{int64(1234)}},
+	)
+	q := &querier{query: query, cursor: &cursorState{column: "id"}}
+	rowScanSlice, columnNames := scanNextRow(t, q, rows)
+
+	_, _, cursorVal, err := q.processRow(rowScanSlice, columnNames)
+	if err != nil {
+		t.Fatalf("processRow returned error: %v", err)
+	}
+	if cursorVal != "1234" {
+		t.Fatalf("expected cursor value 1234, got %q", cursorVal)
+	}
+}
+
+// TestGetRowSlice_MissingDimensionColumn covers getRowSlice's
+// existence-validation error path for a metric dimension column absent
+// from the result set.
+func TestGetRowSlice_MissingDimensionColumn(t *testing.T) {
+	query := &Query{
+		Metrics: []Metric{
+			{MetricName: "requests", ValueColumn: "count", DimensionColumns: []string{"host"}},
+		},
+	}
+	rows := openFakeRows(t, []fakeColumn{{"count", "FLOAT"}}, nil)
+	q := &querier{query: query}
+
+	if _, err := q.getRowSlice(rows); err == nil {
+		t.Fatal("expected an error for a missing dimension column, got nil")
+	}
+}
+
+// TestGetRowSlice_MissingEventColumn covers getRowSlice's existence
+// validation for an event spec's property/dimension columns.
+func TestGetRowSlice_MissingEventColumn(t *testing.T) {
+	query := &Query{
+		Events: []EventSpec{
+			{EventType: "deploy", PropertyColumns: []string{"host"}},
+		},
+	}
+	rows := openFakeRows(t, []fakeColumn{{"other", "VARCHAR"}}, nil)
+	q := &querier{query: query}
+
+	if _, err := q.getRowSlice(rows); err == nil {
+		t.Fatal("expected an error for a missing event column, got nil")
+	}
+}
+
+// TestGetRowSlice_MissingCursorColumn covers getRowSlice's existence
+// validation for the configured Cursor.Column.
+func TestGetRowSlice_MissingCursorColumn(t *testing.T) {
+	query := &Query{
+		Cursor: &Cursor{Column: "id"},
+	}
+	rows := openFakeRows(t, []fakeColumn{{"other", "VARCHAR"}}, nil)
+	q := &querier{query: query, cursor: &cursorState{column: "id"}}
+
+	if _, err := q.getRowSlice(rows); err == nil {
+		t.Fatal("expected an error for a missing cursor column, got nil")
+	}
+}